@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/gruntwork-io/docs/errors"
+	"github.com/spf13/afero"
+)
+
+// copyFile copies the file at srcPath to dstPath on fs.
+func copyFile(fs afero.Fs, srcPath string, dstPath string) error {
+	contents, err := afero.ReadFile(fs, srcPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	info, err := fs.Stat(srcPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := afero.WriteFile(fs, dstPath, contents, info.Mode()); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	return nil
+}
+
+// isFileExist returns true if path exists on fs.
+func isFileExist(fs afero.Fs, path string) bool {
+	exists, err := afero.Exists(fs, path)
+	return err == nil && exists
+}
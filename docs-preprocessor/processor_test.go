@@ -3,8 +3,8 @@ package main
 import (
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
-	"io/ioutil"
 )
 
 const GENERATOR_TESTS_FIXTURES_PATH = "test-fixtures/generator-tests"
@@ -255,21 +255,20 @@ func TestGetModuleDocOutputPath(t *testing.T) {
 func TestCopyFile(t *testing.T) {
 	t.Parallel()
 
-	// Create a tempFile
-	file, err := ioutil.TempFile("", "docs-preprocessor")
-	if err != nil {
-		t.Fatal("Failed to create temp file.")
-	}
-
-	// Add random characters to distinguish the new file from the original
-	srcPath := file.Name()
+	fs := afero.NewMemMapFs()
+	srcPath := "docs-preprocessor-test-src"
 	dstPath := srcPath + "xyz"
 
-	copyFile(srcPath, dstPath)
+	assert.Nil(t, afero.WriteFile(fs, srcPath, []byte("hello"), 0644))
+
+	assert.Nil(t, copyFile(fs, srcPath, dstPath))
 
-	assert.True(t, isFileExist(dstPath), "Expected %s to exist, but no file found at that path.", dstPath)
+	assert.True(t, isFileExist(fs, dstPath), "Expected %s to exist, but no file found at that path.", dstPath)
 }
 
+// TestProcessDocumentationFile remains commented out and has not been ported to afero: it calls
+// getContentsForDocumentationFile without an afero.Fs, and readProcessorTestsFixturesFile reads fixtures from the
+// OS filesystem through ioutil.
 // func TestProcessDocumentationFile(t *testing.T) {
 // 	t.Parallel()
 
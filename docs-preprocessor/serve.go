@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+	"github.com/gruntwork-io/docs/nav"
+	"github.com/spf13/afero"
+)
+
+// LIVERELOAD_SCRIPT is injected into every rendered HTML page in serve mode. It opens a websocket back to this
+// process and reloads the page whenever a rebuild message arrives.
+const LIVERELOAD_SCRIPT = `<script>
+(function() {
+	var socket = new WebSocket("ws://" + window.location.host + "/__livereload");
+	socket.onmessage = function() { window.location.reload(); };
+})();
+</script>`
+
+// ServeOpts configures the `docs serve` subcommand.
+//
+// EnabledFormats is a ServeOpts field, not an Opts field: RunServe resolves it through nav.GetOutputFormats, and
+// nothing outside serve mode reads it.
+type ServeOpts struct {
+	InputPath      string   // the root of the input tree to watch for changes
+	ThemePath      string   // an optional theme directory overlaid on top of InputPath; see nav.NewSourceFs
+	RootOutputPath string   // the root of the rendered output tree, served over HTTP
+	Addr           string   // the address to listen on, e.g. ":1313"
+	EnabledFormats []string // nav.OutputFormat names to re-render affected pages as; empty means HTML only
+}
+
+// RunServe starts an HTTP server rooted at opts.RootOutputPath and watches opts.InputPath with fsnotify. On each
+// change it re-renders only the affected pages (the changed page plus anything contentMap reports as depending
+// on it) instead of the whole corpus, and then notifies every connected browser to reload.
+func RunServe(opts *ServeOpts, contentMap *nav.ContentMap) error {
+	nav.LiveReloadScript = LIVERELOAD_SCRIPT
+
+	formats, err := nav.GetOutputFormats(opts.EnabledFormats)
+	if err != nil {
+		return fmt.Errorf("failed to resolve opts.EnabledFormats: %w", err)
+	}
+
+	assignPageFilesystems(contentMap, nav.NewSourceFs(opts.InputPath, opts.ThemePath), nav.NewDestFs(opts.RootOutputPath))
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start filesystem watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(opts.InputPath); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", opts.InputPath, err)
+	}
+
+	reloadBroadcaster := newReloadBroadcaster()
+
+	go watchAndRebuild(watcher, opts, contentMap, formats, reloadBroadcaster)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(opts.RootOutputPath)))
+	mux.HandleFunc("/__livereload", reloadBroadcaster.serveWs)
+
+	fmt.Printf("Serving %s on %s (watching %s for changes)\n", opts.RootOutputPath, opts.Addr, opts.InputPath)
+
+	return http.ListenAndServe(opts.Addr, mux)
+}
+
+// assignPageFilesystems points every Page already in contentMap at sourceFs/destFs, so the filesystems opts
+// describes (InputPath + ThemePath, RootOutputPath) are the ones actually read from and written to, regardless
+// of what Page.SourceFs/DestFs were set to when the corpus was originally walked.
+func assignPageFilesystems(contentMap *nav.ContentMap, sourceFs afero.Fs, destFs afero.Fs) {
+	contentMap.WalkPrefix("", func(path string, page *nav.Page) {
+		if page == nil {
+			return
+		}
+
+		page.SourceFs = sourceFs
+		page.DestFs = destFs
+	})
+}
+
+// watchAndRebuild consumes fsnotify events until watcher is closed, re-rendering the affected pages for each one.
+func watchAndRebuild(watcher *fsnotify.Watcher, opts *ServeOpts, contentMap *nav.ContentMap, formats []nav.OutputFormat, reloadBroadcaster *reloadBroadcaster) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if !strings.HasSuffix(event.Name, ".md") {
+				continue
+			}
+
+			if err := rebuildAffectedPages(event.Name, opts, contentMap, formats); err != nil {
+				log.Printf("Error rebuilding after change to %s: %v\n", event.Name, err)
+				continue
+			}
+
+			reloadBroadcaster.broadcastReload()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Printf("Watcher error: %v\n", err)
+		}
+	}
+}
+
+// rebuildAffectedPages re-renders the Page at changedFullInputPath plus every Page that contentMap records as
+// depending on it (i.e. every page whose links were previously resolved against it), instead of the whole
+// corpus. Combined with the render cache, unchanged pages are skipped in microseconds. formats is the set of
+// nav.OutputFormats (resolved from opts.EnabledFormats) each affected page is re-rendered as.
+func rebuildAffectedPages(changedFullInputPath string, opts *ServeOpts, contentMap *nav.ContentMap, formats []nav.OutputFormat) error {
+	// fsnotify reports event.Name rooted at opts.InputPath (since that's what watcher.Add was called with), but
+	// Page.FullInputPath is corpus-relative - it's read through SourceFs, a BasePathFs already rooted at
+	// opts.InputPath (see assignPageFilesystems). Strip that prefix before looking the page up or reading through
+	// SourceFs, or every lookup/read double-roots the path and silently no-ops.
+	relativeChangedPath, err := filepath.Rel(opts.InputPath, changedFullInputPath)
+	if err != nil {
+		return fmt.Errorf("failed to make %s relative to %s: %w", changedFullInputPath, opts.InputPath, err)
+	}
+
+	changedPage, exists := contentMap.FindByFullInputPath(relativeChangedPath)
+	if !exists {
+		return nil
+	}
+
+	dependentOutputPaths := contentMap.DependentsOf(changedPage.OutputPath)
+
+	// changedPage's own file just changed, so its cache key already misses on mtime/size. Its dependents'
+	// files didn't change, so they need forceRefresh=true or their cached (and now stale) links to changedPage
+	// would never be recomputed.
+	affectedOutputPaths := []string{changedPage.OutputPath}
+	affectedOutputPaths = append(affectedOutputPaths, dependentOutputPaths...)
+
+	for _, outputPath := range affectedOutputPaths {
+		page, exists := contentMap.Get(outputPath)
+		if !exists {
+			continue
+		}
+
+		forceRefresh := outputPath != changedPage.OutputPath
+
+		// Every enabled format gets its own resolved links baked in, one format at a time: PopulateAllProperties
+		// re-populates p.BodyMarkdown/p.BodyHtml with links resolved against this format's extension (the cache
+		// key includes linkFormat, so switching formats is a clean miss rather than a stale hit), and the page is
+		// written to disk for that format before the next one repopulates it.
+		for _, format := range formats {
+			if err := page.PopulateAllProperties(contentMap, forceRefresh, format); err != nil {
+				return err
+			}
+
+			// page.DestFs (see assignPageFilesystems) is already a BasePathFs rooted at opts.RootOutputPath, so
+			// the target path written through it must be corpus-relative; passing opts.RootOutputPath again here
+			// would join it onto the path a second time.
+			if err := page.WriteFullPageHtmlToOutputPath(contentMap, "", []nav.OutputFormat{format}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reloadBroadcaster tracks every browser currently connected to /__livereload and fans a reload message out to
+// all of them after a successful rebuild.
+type reloadBroadcaster struct {
+	mu       sync.Mutex
+	upgrader websocket.Upgrader
+	conns    map[*websocket.Conn]bool
+}
+
+func newReloadBroadcaster() *reloadBroadcaster {
+	return &reloadBroadcaster{
+		conns: make(map[*websocket.Conn]bool),
+	}
+}
+
+// serveWs upgrades the incoming request to a websocket and keeps it registered until the client disconnects.
+func (b *reloadBroadcaster) serveWs(w http.ResponseWriter, r *http.Request) {
+	conn, err := b.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade livereload connection: %v\n", err)
+		return
+	}
+
+	b.mu.Lock()
+	b.conns[conn] = true
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+
+	// Block until the client disconnects; we never expect to receive anything on this connection.
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// broadcastReload sends a reload message to every currently connected browser.
+func (b *reloadBroadcaster) broadcastReload() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn := range b.conns {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("reload")); err != nil {
+			log.Printf("Failed to send reload message: %v\n", err)
+		}
+	}
+}
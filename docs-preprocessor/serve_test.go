@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/docs/nav"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebuildAffectedPagesStripsWatcherInputPathPrefix(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(fs, "_html/doc_template.html", []byte("<html><body>{{.PageBody}}</body></html>"), 0644))
+	assert.Nil(t, afero.WriteFile(fs, "global/help/support.md", []byte("body"), 0644))
+
+	page := nav.NewPage(&nav.File{
+		InputPath:     "global/help/support.md",
+		FullInputPath: "global/help/support.md",
+		OutputPath:    "global/help/support.md",
+	}, fs, fs)
+
+	contentMap := nav.NewContentMap()
+	contentMap.AddPage(page)
+
+	opts := &ServeOpts{InputPath: "/corpus", RootOutputPath: ""}
+	formats, err := nav.GetOutputFormats(nil)
+	assert.Nil(t, err)
+
+	// fsnotify reports event.Name rooted at opts.InputPath, e.g. "/corpus/global/help/support.md", even though
+	// page.FullInputPath ("global/help/support.md") is corpus-relative. rebuildAffectedPages must strip that
+	// prefix before it can find the page or read/write through its (corpus-relative) SourceFs/DestFs.
+	err = rebuildAffectedPages("/corpus/global/help/support.md", opts, contentMap, formats)
+	assert.Nil(t, err)
+
+	exists, err := afero.Exists(fs, "global/help/support.html")
+	assert.Nil(t, err)
+	assert.True(t, exists, "expected global/help/support.html to have been written")
+}
+
+func TestRebuildAffectedPagesReturnsNilForUntrackedPath(t *testing.T) {
+	t.Parallel()
+
+	contentMap := nav.NewContentMap()
+	opts := &ServeOpts{InputPath: "/corpus"}
+	formats, err := nav.GetOutputFormats(nil)
+	assert.Nil(t, err)
+
+	err = rebuildAffectedPages("/corpus/does/not/exist.md", opts, contentMap, formats)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,204 @@
+package nav
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/gruntwork-io/docs/errors"
+	"github.com/spf13/afero"
+)
+
+// DOCS_MEMORYLIMIT_ENV_VAR overrides the default cache budget. It holds a float number of gigabytes, e.g. "2.5".
+const DOCS_MEMORYLIMIT_ENV_VAR = "DOCS_MEMORYLIMIT"
+
+// DEFAULT_CACHE_BUDGET_BYTES is the hard cap on the default cache's size: 1 GiB.
+const DEFAULT_CACHE_BUDGET_BYTES = int64(1) << 30
+
+// A cacheKey identifies a cached render by the file that produced it. mtime and size are included so that an
+// edited file is treated as a cache miss rather than returning stale content, without the cost of hashing the
+// file body on every lookup. linkFormat is included because the cached markdown/html have that format's extension
+// baked into every resolved cross-reference link, so a lookup for a different format must miss rather than
+// silently returning links resolved for the wrong format.
+type cacheKey struct {
+	fullInputPath string
+	modTimeUnix   int64
+	size          int64
+	linkFormat    string
+}
+
+// cacheKeyForFile stats fullInputPath on fs and returns the cacheKey current processing of that file should use
+// when its cross-reference links are being resolved against linkFormat.
+func cacheKeyForFile(fs afero.Fs, fullInputPath string, linkFormat OutputFormat) (cacheKey, error) {
+	info, err := fs.Stat(fullInputPath)
+	if err != nil {
+		return cacheKey{}, errors.WithStackTrace(err)
+	}
+
+	return cacheKey{
+		fullInputPath: fullInputPath,
+		modTimeUnix:   info.ModTime().Unix(),
+		size:          info.Size(),
+		linkFormat:    linkFormat.Name,
+	}, nil
+}
+
+// cacheEntry holds one page's already-processed link-rewritten Markdown and rendered HTML, plus its place in the
+// LRU eviction order.
+type cacheEntry struct {
+	key      cacheKey
+	markdown string
+	html     string
+	prev     *cacheEntry
+	next     *cacheEntry
+}
+
+// approxBytes is the approximate memory cost of caching this entry: the stored strings plus a flat overhead for
+// the key and linked-list bookkeeping.
+func (e *cacheEntry) approxBytes() int64 {
+	const perEntryOverheadBytes = 128
+	return int64(len(e.markdown)+len(e.html)) + perEntryOverheadBytes
+}
+
+// CacheStats reports how effectively a Cache is being used, for benchmarking.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	UsedBytes int64
+}
+
+// Cache is a shared in-process LRU cache of markdown-to-HTML and link-rewrite results, keyed by
+// (FullInputPath, mtime, size, link format). It is bounded by a byte budget rather than an entry count, since rendered pages
+// vary wildly in size. Page.PopulateAllProperties reads through it, so re-processing an unchanged file during a
+// watch/rebuild is a pointer lookup instead of a re-render.
+type Cache struct {
+	mu         sync.Mutex
+	budget     int64
+	usedBytes  int64
+	entries    map[cacheKey]*cacheEntry
+	head, tail *cacheEntry // head = most recently used, tail = least recently used
+	hits       int64
+	misses     int64
+}
+
+// NewCache returns an empty Cache that evicts least-recently-used entries on Set until usedBytes <= budgetBytes.
+func NewCache(budgetBytes int64) *Cache {
+	return &Cache{
+		budget:  budgetBytes,
+		entries: make(map[cacheKey]*cacheEntry),
+	}
+}
+
+// defaultCache is the process-wide Cache that Page.PopulateAllProperties reads through.
+var defaultCache = NewCache(DefaultCacheBudgetBytes())
+
+// DefaultCacheBudgetBytes returns the byte budget for defaultCache: min(1 GiB, totalSystemMemory/4), unless
+// overridden by the DOCS_MEMORYLIMIT env var.
+func DefaultCacheBudgetBytes() int64 {
+	if raw := os.Getenv(DOCS_MEMORYLIMIT_ENV_VAR); raw != "" {
+		if gigabytes, err := strconv.ParseFloat(raw, 64); err == nil {
+			return int64(gigabytes * float64(int64(1)<<30))
+		}
+	}
+
+	quarterOfSystemMemory := totalSystemMemoryBytes() / 4
+	if quarterOfSystemMemory < DEFAULT_CACHE_BUDGET_BYTES {
+		return quarterOfSystemMemory
+	}
+
+	return DEFAULT_CACHE_BUDGET_BYTES
+}
+
+// Get returns the cached Markdown and HTML for key, if present, moving it to the front of the LRU order.
+func (c *Cache) Get(key cacheKey) (markdown string, html string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		c.misses++
+		return "", "", false
+	}
+
+	c.hits++
+	c.moveToFront(entry)
+
+	return entry.markdown, entry.html, true
+}
+
+// Set stores markdown and html under key, evicting least-recently-used entries until the cache fits its budget.
+func (c *Cache) Set(key cacheKey, markdown string, html string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, exists := c.entries[key]; exists {
+		c.usedBytes -= existing.approxBytes()
+		c.unlink(existing)
+		delete(c.entries, key)
+	}
+
+	entry := &cacheEntry{key: key, markdown: markdown, html: html}
+	c.entries[key] = entry
+	c.usedBytes += entry.approxBytes()
+	c.pushFront(entry)
+
+	for c.usedBytes > c.budget && c.tail != nil {
+		evicted := c.tail
+		c.usedBytes -= evicted.approxBytes()
+		c.unlink(evicted)
+		delete(c.entries, evicted.key)
+	}
+}
+
+// Stats returns the current hit/miss/byte-usage counters, for benchmarking.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		UsedBytes: c.usedBytes,
+	}
+}
+
+// pushFront inserts entry at the most-recently-used end of the LRU list. Callers must hold c.mu.
+func (c *Cache) pushFront(entry *cacheEntry) {
+	entry.prev = nil
+	entry.next = c.head
+	if c.head != nil {
+		c.head.prev = entry
+	}
+	c.head = entry
+	if c.tail == nil {
+		c.tail = entry
+	}
+}
+
+// unlink removes entry from the LRU list without deleting it from the entries map. Callers must hold c.mu.
+func (c *Cache) unlink(entry *cacheEntry) {
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		c.head = entry.next
+	}
+
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		c.tail = entry.prev
+	}
+
+	entry.prev = nil
+	entry.next = nil
+}
+
+// moveToFront marks entry as the most-recently-used. Callers must hold c.mu.
+func (c *Cache) moveToFront(entry *cacheEntry) {
+	if c.head == entry {
+		return
+	}
+
+	c.unlink(entry)
+	c.pushFront(entry)
+}
@@ -0,0 +1,53 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheGetSetRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(DEFAULT_CACHE_BUDGET_BYTES)
+	key := cacheKey{fullInputPath: "a.md", modTimeUnix: 1, size: 2}
+
+	_, _, ok := cache.Get(key)
+	assert.False(t, ok)
+
+	cache.Set(key, "# a", "<h1>a</h1>")
+
+	markdown, html, ok := cache.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, "# a", markdown)
+	assert.Equal(t, "<h1>a</h1>", html)
+}
+
+func TestCacheEvictsLeastRecentlyUsedWhenOverBudget(t *testing.T) {
+	t.Parallel()
+
+	keyA := cacheKey{fullInputPath: "a.md", modTimeUnix: 1, size: 1}
+	keyB := cacheKey{fullInputPath: "b.md", modTimeUnix: 1, size: 1}
+	keyC := cacheKey{fullInputPath: "c.md", modTimeUnix: 1, size: 1}
+
+	entryBytes := (&cacheEntry{markdown: "x", html: "y"}).approxBytes()
+	cache := NewCache(entryBytes * 2)
+
+	cache.Set(keyA, "x", "y")
+	cache.Set(keyB, "x", "y")
+
+	// Touch A so B becomes the least-recently-used entry.
+	_, _, ok := cache.Get(keyA)
+	assert.True(t, ok)
+
+	cache.Set(keyC, "x", "y")
+
+	_, _, ok = cache.Get(keyB)
+	assert.False(t, ok, "B should have been evicted as the least-recently-used entry")
+
+	_, _, ok = cache.Get(keyA)
+	assert.True(t, ok)
+
+	_, _, ok = cache.Get(keyC)
+	assert.True(t, ok)
+}
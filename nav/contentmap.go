@@ -0,0 +1,297 @@
+package nav
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/armon/go-radix"
+	"github.com/gruntwork-io/docs/errors"
+)
+
+const SHORTHAND_REF_REGEX = `^(module|example):([\w-]+)/([\w-]+)$`
+
+// A contentNode is a single entry in a ContentMap: either a leaf Page, or a plain folder bundle with no Page of
+// its own (e.g. an intermediate directory that has no README.md).
+type contentNode struct {
+	path string
+	page *Page
+}
+
+// ContentMap is a radix tree of every Page and folder in the corpus, keyed by OutputPath. It replaces the
+// Folder-of-Page recursive tree that AddToNavTree/GetAsNavTreeHtml used to walk: O(k) lookups by path, cheap
+// prefix walks for sub-tree nav rendering, and cheap "nearest ancestor with a README" lookups for
+// breadcrumb/parent linking. It also sidesteps the duplicate-folder bugs the old getContainingFolder-plus-linear
+// search had when paths differed only in trailing slashes, since Insert normalizes keys (see normalizePath).
+type ContentMap struct {
+	tree *radix.Tree
+
+	dependentsMu sync.Mutex
+	// dependents maps a Page's OutputPath to the set of OutputPaths whose rendered links point at it. It's
+	// populated by RecordDependency as links are resolved, and consulted by the watch-mode server to know which
+	// other pages need to be re-rendered when a given page changes.
+	dependents map[string]map[string]bool
+}
+
+// NewContentMap returns an empty ContentMap.
+func NewContentMap() *ContentMap {
+	return &ContentMap{
+		tree:       radix.New(),
+		dependents: make(map[string]map[string]bool),
+	}
+}
+
+// Insert adds page at the given path, creating any intermediate folder nodes along the way.
+func (m *ContentMap) Insert(path string, page *Page) {
+	m.CreateFolderIfNotExist(path)
+	m.tree.Insert(normalizePath(path), &contentNode{path: normalizePath(path), page: page})
+}
+
+// Get returns the Page stored at path, if any. It returns false for folder-only nodes that have no Page.
+func (m *ContentMap) Get(path string) (*Page, bool) {
+	raw, exists := m.tree.Get(normalizePath(path))
+	if !exists {
+		return nil, false
+	}
+
+	node := raw.(*contentNode)
+	if node.page == nil {
+		return nil, false
+	}
+
+	return node.page, true
+}
+
+// WalkPrefix invokes fn for every node (page or folder) whose path starts with prefix, in lexical order.
+func (m *ContentMap) WalkPrefix(prefix string, fn func(path string, page *Page)) {
+	m.tree.WalkPrefix(normalizePath(prefix), func(path string, raw interface{}) bool {
+		node := raw.(*contentNode)
+		fn(node.path, node.page)
+		return false
+	})
+}
+
+// Ancestors returns the Pages of every ancestor folder of path that has an overview page (a README.md directly
+// in that folder), closest first. This is the lookup breadcrumb/parent linking uses to find the nearest ancestor
+// overview page. Folders are only ever inserted into the tree as plain folder nodes with no Page of their own
+// (see CreateFolderIfNotExist) - the folder's own overview page, if any, lives one level down at
+// "<folder>/README.md", mirroring the convention getByShorthand also resolves against.
+func (m *ContentMap) Ancestors(path string) []*Page {
+	var pages []*Page
+
+	normalizedPath := normalizePath(path)
+	ancestorPaths := ancestorsOf(normalizedPath)
+	for i := len(ancestorPaths) - 1; i >= 0; i-- {
+		candidatePath := ancestorPaths[i] + "/README.md"
+		if candidatePath == normalizedPath {
+			// path's own immediate folder - if path is itself that folder's README.md, it is not its own
+			// ancestor.
+			continue
+		}
+
+		if page, exists := m.Get(candidatePath); exists {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages
+}
+
+// CreateFolderIfNotExist ensures every folder along path exists in the map, mirroring the old Folder tree's
+// method of the same name. It is a no-op if the folders are already present.
+func (m *ContentMap) CreateFolderIfNotExist(path string) {
+	for _, ancestorPath := range ancestorsOf(normalizePath(path)) {
+		if _, exists := m.tree.Get(ancestorPath); !exists {
+			m.tree.Insert(ancestorPath, &contentNode{path: ancestorPath})
+		}
+	}
+}
+
+// AddPage inserts page into the map at its OutputPath, mirroring the old Folder.AddPage method.
+func (m *ContentMap) AddPage(page *Page) {
+	m.Insert(page.OutputPath, page)
+}
+
+// GetAsNavTreeHtml renders this ContentMap as nested <ul>/<li> HTML, marking activePage (if non-nil) with the
+// "active" class.
+func (m *ContentMap) GetAsNavTreeHtml(activePage *Page) template.HTML {
+	var buf bytes.Buffer
+
+	buf.WriteString("<ul>")
+	m.writeNavTreeLevel(&buf, "", activePage)
+	buf.WriteString("</ul>")
+
+	return template.HTML(buf.String())
+}
+
+// writeNavTreeLevel writes the <li> entries for every direct child of prefix, recursing into sub-folders.
+func (m *ContentMap) writeNavTreeLevel(buf *bytes.Buffer, prefix string, activePage *Page) {
+	for _, childPath := range m.directChildren(prefix) {
+		raw, _ := m.tree.Get(childPath)
+		node := raw.(*contentNode)
+
+		class := ""
+		if activePage != nil && node.page != nil && node.page.OutputPath == activePage.OutputPath {
+			class = ` class="active"`
+		}
+
+		label := filepath.Base(childPath)
+		if node.page != nil {
+			label = node.page.Title
+		}
+
+		fmt.Fprintf(buf, "<li%s>%s", class, label)
+
+		if m.hasChildren(childPath) {
+			buf.WriteString("<ul>")
+			m.writeNavTreeLevel(buf, childPath, activePage)
+			buf.WriteString("</ul>")
+		}
+
+		buf.WriteString("</li>")
+	}
+}
+
+// directChildren returns the immediate children of prefix (folders and pages exactly one path segment below
+// prefix), sorted lexically.
+func (m *ContentMap) directChildren(prefix string) []string {
+	// WalkPrefix does a raw string-prefix match, not a path-segment match, so "packages/module-vpc" would also
+	// match a sibling like "packages/module-vpc-extra/README.md". Walking with a trailing "/" scopes the match
+	// to actual descendants of prefix. The empty (root) prefix needs no separator: everything is a descendant.
+	walkPrefix := prefix
+	depth := 0
+	if prefix != "" {
+		walkPrefix = prefix + "/"
+		depth = len(strings.Split(prefix, "/"))
+	}
+
+	var children []string
+	m.tree.WalkPrefix(walkPrefix, func(path string, raw interface{}) bool {
+		if len(strings.Split(path, "/")) == depth+1 {
+			children = append(children, path)
+		}
+
+		return false
+	})
+
+	sort.Strings(children)
+
+	return children
+}
+
+// hasChildren returns true if path has at least one direct child in the map.
+func (m *ContentMap) hasChildren(path string) bool {
+	return len(m.directChildren(path)) > 0
+}
+
+// ancestorsOf returns every ancestor directory of path (not including path itself), root first.
+func ancestorsOf(path string) []string {
+	components := strings.Split(path, "/")
+
+	var ancestorPaths []string
+	for i := 1; i < len(components); i++ {
+		ancestorPaths = append(ancestorPaths, strings.Join(components[:i], "/"))
+	}
+
+	return ancestorPaths
+}
+
+// normalizePath strips leading/trailing slashes so that paths differing only in a trailing slash map to the
+// same radix tree key.
+func normalizePath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// GetPage resolves ref to a Page in this ContentMap, trying in order: (a) ref as an absolute OutputPath,
+// (b) ref as a path relative to from's OutputPath, and (c) a shorthand like "module:module-vpc/vpc-app" or
+// "example:module-vpc/vpc-app". from may be nil, in which case (b) is skipped.
+func (m *ContentMap) GetPage(ref string, from *Page) (*Page, error) {
+	if page, exists := m.Get(ref); exists {
+		return page, nil
+	}
+
+	if from != nil && !strings.HasPrefix(ref, "/") {
+		relativePath := filepath.Join(filepath.Dir(from.OutputPath), ref)
+		if page, exists := m.Get(relativePath); exists {
+			return page, nil
+		}
+	}
+
+	if page, exists := m.getByShorthand(ref); exists {
+		return page, nil
+	}
+
+	return nil, errors.WithStackTrace(&UnresolvedRefErr{ref: ref})
+}
+
+// getByShorthand resolves refs of the form "module:<package>/<name>" or "example:<package>/<name>" to that
+// module's or example's README page. Both kinds resolve to the same OutputPath shape: the "modules/"/"examples/"
+// segment of the InputPath doesn't survive into OutputPath (see getModuleDocOutputPath), so the kind itself only
+// matters for validating the ref's syntax, not for building the candidate path.
+func (m *ContentMap) getByShorthand(ref string) (*Page, bool) {
+	regex := regexp.MustCompile(SHORTHAND_REF_REGEX)
+	submatches := regex.FindStringSubmatch(ref)
+	if submatches == nil {
+		return nil, false
+	}
+
+	packageName, name := submatches[2], submatches[3]
+
+	candidatePath := fmt.Sprintf("packages/%s/%s/README.md", packageName, name)
+
+	return m.Get(candidatePath)
+}
+
+// RecordDependency notes that fromOutputPath's rendered links point at toOutputPath, so that a later change to
+// toOutputPath can be propagated back to fromOutputPath by DependentsOf.
+func (m *ContentMap) RecordDependency(fromOutputPath, toOutputPath string) {
+	m.dependentsMu.Lock()
+	defer m.dependentsMu.Unlock()
+
+	if m.dependents[toOutputPath] == nil {
+		m.dependents[toOutputPath] = make(map[string]bool)
+	}
+
+	m.dependents[toOutputPath][fromOutputPath] = true
+}
+
+// DependentsOf returns the OutputPath of every page whose rendered links point at outputPath.
+func (m *ContentMap) DependentsOf(outputPath string) []string {
+	m.dependentsMu.Lock()
+	defer m.dependentsMu.Unlock()
+
+	var dependentPaths []string
+	for dependentPath := range m.dependents[outputPath] {
+		dependentPaths = append(dependentPaths, dependentPath)
+	}
+
+	return dependentPaths
+}
+
+// FindByFullInputPath returns the Page whose File.FullInputPath matches fullInputPath, if any. It's used by the
+// watch-mode server to map a raw fsnotify path back to the Page that needs re-rendering.
+func (m *ContentMap) FindByFullInputPath(fullInputPath string) (*Page, bool) {
+	var found *Page
+
+	m.WalkPrefix("", func(path string, page *Page) {
+		if found == nil && page != nil && page.FullInputPath == fullInputPath {
+			found = page
+		}
+	})
+
+	return found, found != nil
+}
+
+// UnresolvedRefErr is returned by GetPage when ref could not be resolved by any of its supported strategies.
+type UnresolvedRefErr struct {
+	ref string
+}
+
+func (err UnresolvedRefErr) Error() string {
+	return fmt.Sprintf("Could not resolve ref '%s' to any page in the ContentMap", err.ref)
+}
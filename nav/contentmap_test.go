@@ -0,0 +1,121 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPage(outputPath string) *Page {
+	return &Page{
+		File: File{
+			InputPath:     outputPath,
+			FullInputPath: outputPath,
+			OutputPath:    outputPath,
+		},
+		Title: outputPath,
+	}
+}
+
+func TestContentMapDirectChildrenDoesNotMatchSiblingsByNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	contentMap.AddPage(newTestPage("packages/module-vpc/README.md"))
+	contentMap.AddPage(newTestPage("packages/module-vpc-extra/README.md"))
+
+	children := contentMap.directChildren("packages/module-vpc")
+
+	assert.Equal(t, []string{"packages/module-vpc/README.md"}, children, "packages/module-vpc-extra should not be treated as a child of packages/module-vpc")
+}
+
+func TestContentMapGetPageResolvesAbsolutePath(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	page := newTestPage("global/help/support.md")
+	contentMap.AddPage(page)
+
+	resolved, err := contentMap.GetPage("global/help/support.md", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, page, resolved)
+}
+
+func TestContentMapGetPageResolvesRelativePath(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	target := newTestPage("global/help/support.md")
+	contentMap.AddPage(target)
+
+	from := newTestPage("global/introduction/overview.md")
+
+	resolved, err := contentMap.GetPage("../help/support.md", from)
+	assert.Nil(t, err)
+	assert.Equal(t, target, resolved)
+}
+
+func TestContentMapGetPageResolvesModuleAndExampleShorthand(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	target := newTestPage("packages/module-vpc/vpc-app/README.md")
+	contentMap.AddPage(target)
+
+	moduleResolved, err := contentMap.GetPage("module:module-vpc/vpc-app", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, target, moduleResolved)
+
+	exampleResolved, err := contentMap.GetPage("example:module-vpc/vpc-app", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, target, exampleResolved)
+}
+
+func TestContentMapGetPageReturnsErrorForUnresolvedRef(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+
+	_, err := contentMap.GetPage("module:does-not-exist/vpc-app", nil)
+	assert.NotNil(t, err)
+}
+
+func TestContentMapAncestorsReturnsClosestFirst(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	top := newTestPage("packages/README.md")
+	root := newTestPage("packages/module-vpc/README.md")
+	sub := newTestPage("packages/module-vpc/vpc-app/README.md")
+	contentMap.AddPage(top)
+	contentMap.AddPage(root)
+	contentMap.AddPage(sub)
+
+	ancestors := contentMap.Ancestors("packages/module-vpc/vpc-app/README.md")
+
+	assert.Equal(t, []*Page{root, top}, ancestors, "packages/module-vpc is closer than packages, so its README should come first")
+}
+
+func TestContentMapAncestorsReturnsEmptyWhenNoAncestorHasAPage(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	contentMap.AddPage(newTestPage("packages/module-vpc/vpc-app/README.md"))
+
+	ancestors := contentMap.Ancestors("packages/module-vpc/vpc-app/README.md")
+
+	assert.Empty(t, ancestors)
+}
+
+func TestTargetPathBuilderBuildUrlIsRootRelative(t *testing.T) {
+	t.Parallel()
+
+	page := newTestPage("packages/module-vpc/vpc-app/README.md")
+	format, err := GetOutputFormat("html")
+	assert.Nil(t, err)
+
+	builder := &TargetPathBuilder{RootOutputPath: "/some/disk/path"}
+	url := builder.BuildUrl(page, format)
+
+	assert.Equal(t, "/packages/module-vpc/vpc-app/README.html", url, "BuildUrl should ignore RootOutputPath and always be rooted at /")
+}
@@ -0,0 +1,31 @@
+package nav
+
+import (
+	"github.com/spf13/afero"
+)
+
+// NewSourceFs returns the afero.Fs that Page reads input Markdown and the theme's HTML template from. baseDir is
+// the corpus root. If themeDir is non-empty, it is overlaid underneath baseDir via a union filesystem, so a
+// "_html/doc_template.html" at baseDir/HTML_TEMPLATE_REL_PATH is found in preference to one in the theme, and
+// the theme's copy is only used as a fallback when the corpus doesn't have its own. This replaces the
+// hard-coded assumption that HTML_TEMPLATE_REL_PATH always lives alongside the input tree.
+func NewSourceFs(baseDir string, themeDir string) afero.Fs {
+	base := afero.NewBasePathFs(afero.NewOsFs(), baseDir)
+
+	if themeDir == "" {
+		return base
+	}
+
+	theme := afero.NewReadOnlyFs(afero.NewBasePathFs(afero.NewOsFs(), themeDir))
+
+	// CopyOnWriteFs(base, layer) checks layer first for both reads and writes, falling through to base only when
+	// layer doesn't have the path. We want the corpus itself to win over the theme on read, and any write to land
+	// on the corpus (the theme's BasePathFs is read-only and would error), so base is passed theme and layer is
+	// passed the corpus.
+	return afero.NewCopyOnWriteFs(theme, base)
+}
+
+// NewDestFs returns the afero.Fs that WriteFullPageHtmlToOutputPath writes rendered output to.
+func NewDestFs(outputDir string) afero.Fs {
+	return afero.NewBasePathFs(afero.NewOsFs(), outputDir)
+}
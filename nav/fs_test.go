@@ -0,0 +1,61 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSourceFsCorpusFileWinsOverTheme(t *testing.T) {
+	t.Parallel()
+
+	corpusDir := t.TempDir()
+	themeDir := t.TempDir()
+
+	assert.Nil(t, afero.NewOsFs().MkdirAll(corpusDir+"/_html", 0755))
+	assert.Nil(t, afero.NewOsFs().MkdirAll(themeDir+"/_html", 0755))
+	assert.Nil(t, afero.WriteFile(afero.NewOsFs(), corpusDir+"/_html/doc_template.html", []byte("corpus template"), 0644))
+	assert.Nil(t, afero.WriteFile(afero.NewOsFs(), themeDir+"/_html/doc_template.html", []byte("theme template"), 0644))
+
+	fs := NewSourceFs(corpusDir, themeDir)
+
+	contents, err := afero.ReadFile(fs, "_html/doc_template.html")
+	assert.Nil(t, err)
+	assert.Equal(t, "corpus template", string(contents))
+}
+
+func TestNewSourceFsFallsBackToThemeWhenCorpusLacksFile(t *testing.T) {
+	t.Parallel()
+
+	corpusDir := t.TempDir()
+	themeDir := t.TempDir()
+
+	assert.Nil(t, afero.NewOsFs().MkdirAll(themeDir+"/_html", 0755))
+	assert.Nil(t, afero.WriteFile(afero.NewOsFs(), themeDir+"/_html/doc_template.html", []byte("theme template"), 0644))
+
+	fs := NewSourceFs(corpusDir, themeDir)
+
+	contents, err := afero.ReadFile(fs, "_html/doc_template.html")
+	assert.Nil(t, err)
+	assert.Equal(t, "theme template", string(contents))
+}
+
+func TestNewSourceFsWritesLandOnCorpusNotTheme(t *testing.T) {
+	t.Parallel()
+
+	corpusDir := t.TempDir()
+	themeDir := t.TempDir()
+
+	fs := NewSourceFs(corpusDir, themeDir)
+
+	assert.Nil(t, afero.WriteFile(fs, "notes.md", []byte("new notes"), 0644))
+
+	corpusContents, err := afero.ReadFile(afero.NewOsFs(), corpusDir+"/notes.md")
+	assert.Nil(t, err)
+	assert.Equal(t, "new notes", string(corpusContents))
+
+	exists, err := afero.Exists(afero.NewOsFs(), themeDir+"/notes.md")
+	assert.Nil(t, err)
+	assert.False(t, exists, "write should not have landed on the read-only theme layer")
+}
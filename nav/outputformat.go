@@ -0,0 +1,199 @@
+package nav
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/docs/errors"
+)
+
+// An OutputFormat describes one way a Page can be rendered to disk: HTML, plain Markdown with resolved links,
+// a JSON index entry, Gemtext, etc. Registering a new OutputFormat here is the only thing required to add a new
+// publishing target for the whole corpus.
+type OutputFormat struct {
+	Name      string // e.g. "html", "markdown", "json", "gemtext"
+	MediaType string // e.g. "text/html"
+	Extension string // output file extension, without the leading dot
+	Render    func(p *Page, contentMap *ContentMap) (string, error) // renders p into this format's output bytes
+}
+
+// DEFAULT_OUTPUT_FORMAT_NAMES is what a run publishes when ServeOpts.EnabledFormats is empty, preserving the original
+// HTML-only behavior.
+var DEFAULT_OUTPUT_FORMAT_NAMES = []string{"html"}
+
+// outputFormatRegistry is the set of formats a Page can be rendered into, keyed by name.
+var outputFormatRegistry = map[string]OutputFormat{
+	"html": {
+		Name:      "html",
+		MediaType: "text/html",
+		Extension: "html",
+		Render:    renderHtmlOutputFormat,
+	},
+	"markdown": {
+		Name:      "markdown",
+		MediaType: "text/markdown",
+		Extension: "md",
+		Render:    renderMarkdownOutputFormat,
+	},
+	"json": {
+		Name:      "json",
+		MediaType: "application/json",
+		Extension: "json",
+		Render:    renderJsonOutputFormat,
+	},
+	"gemtext": {
+		Name:      "gemtext",
+		MediaType: "text/gemini",
+		Extension: "gmi",
+		Render:    renderGemtextOutputFormat,
+	},
+}
+
+// GetOutputFormat looks up a registered OutputFormat by name.
+func GetOutputFormat(name string) (OutputFormat, error) {
+	format, exists := outputFormatRegistry[name]
+	if !exists {
+		return format, errors.WithStackTrace(&UnknownOutputFormatErr{name: name})
+	}
+
+	return format, nil
+}
+
+// GetOutputFormats resolves a list of format names (e.g. ServeOpts.EnabledFormats) into OutputFormat instances. An
+// empty names list resolves to DEFAULT_OUTPUT_FORMAT_NAMES so existing HTML-only behavior is preserved.
+func GetOutputFormats(names []string) ([]OutputFormat, error) {
+	if len(names) == 0 {
+		names = DEFAULT_OUTPUT_FORMAT_NAMES
+	}
+
+	formats := make([]OutputFormat, 0, len(names))
+	for _, name := range names {
+		format, err := GetOutputFormat(name)
+		if err != nil {
+			return nil, err
+		}
+
+		formats = append(formats, format)
+	}
+
+	return formats, nil
+}
+
+// A TargetPathBuilder computes the final on-disk path for a Page rendered into a given OutputFormat. It replaces
+// the ad-hoc replaceMdFileExtensionWithHtmlFileExtension, which only ever knew how to produce ".html" paths.
+type TargetPathBuilder struct {
+	RootOutputPath string
+}
+
+// Build returns the path at which p should be written when rendered as format: p's corpus-relative rendered path
+// joined onto RootOutputPath. It is absolute only when RootOutputPath is.
+func (b *TargetPathBuilder) Build(p *Page, format OutputFormat) string {
+	return filepath.Join(b.RootOutputPath, b.relPath(p, format))
+}
+
+// BuildUrl returns the root-relative URL (e.g. "/packages/module-vpc/vpc-app/README.html") at which p is served
+// once rendered as format. Unlike Build, this is meant to be spliced directly into rendered output as an href,
+// so it's always rooted at "/" regardless of RootOutputPath, rather than being resolved against the directory
+// of whichever page happens to link to it.
+func (b *TargetPathBuilder) BuildUrl(p *Page, format OutputFormat) string {
+	return "/" + b.relPath(p, format)
+}
+
+// relPath returns p's rendered path as format, relative to the corpus root.
+func (b *TargetPathBuilder) relPath(p *Page, format OutputFormat) string {
+	dir := filepath.Dir(p.OutputPath)
+	base := strings.TrimSuffix(filepath.Base(p.OutputPath), filepath.Ext(p.OutputPath))
+
+	return filepath.Join(dir, fmt.Sprintf("%s.%s", base, format.Extension))
+}
+
+// Render this Page as a full HTML document (title, nav tree, body).
+func renderHtmlOutputFormat(p *Page, contentMap *ContentMap) (string, error) {
+	bodyHtml := p.getBodyHtml()
+	navTreeHtml := p.getNavTreeHtml(contentMap)
+
+	return getFullHtml(p.SourceFs, bodyHtml, navTreeHtml, p.Title)
+}
+
+// Render this Page as Markdown with all relative links resolved to fully qualified URLs.
+func renderMarkdownOutputFormat(p *Page, contentMap *ContentMap) (string, error) {
+	return p.BodyMarkdown, nil
+}
+
+// Render this Page as a JSON document containing its title, body, position in the nav tree, and breadcrumb trail.
+func renderJsonOutputFormat(p *Page, contentMap *ContentMap) (string, error) {
+	type jsonPage struct {
+		Title       string   `json:"title"`
+		Body        string   `json:"body"`
+		NavTree     string   `json:"navtree"`
+		Breadcrumbs []string `json:"breadcrumbs"`
+	}
+
+	out, err := json.Marshal(&jsonPage{
+		Title:       p.Title,
+		Body:        p.BodyMarkdown,
+		NavTree:     string(p.getNavTreeHtml(contentMap)),
+		Breadcrumbs: p.getBreadcrumbTitles(contentMap),
+	})
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return string(out), nil
+}
+
+// markdownLinkRegex matches an inline Markdown link like "[label](url)". It does not handle link targets
+// containing a literal ")" (e.g. Wikipedia-style URLs), code spans/fences, or Markdown images
+// ("![label](url)") - renderGemtextOutputFormat special-cases the "!" prefix to leave images untouched.
+var markdownLinkRegex = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+
+// Render this Page as a bare-bones Gemtext document. Markdown headings (#, ##, ...) are already valid Gemtext
+// line syntax and pass through unchanged. Gemtext has no inline link syntax, so each inline Markdown link is
+// pulled out of its surrounding line, left behind as plain label text, and emitted as its own "=> url label"
+// link line directly below. Markdown images are left as-is, since Gemtext has no inline image syntax either
+// and there's nowhere sensible to relocate them to.
+func renderGemtextOutputFormat(p *Page, contentMap *ContentMap) (string, error) {
+	lines := strings.Split(p.BodyMarkdown, "\n")
+	gemtextLines := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		var linkLines []string
+
+		plainLine := ""
+		lastEnd := 0
+
+		for _, match := range markdownLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+			start, end := match[0], match[1]
+			if start > 0 && line[start-1] == '!' {
+				continue
+			}
+
+			label := line[match[2]:match[3]]
+			url := line[match[4]:match[5]]
+
+			plainLine += line[lastEnd:start] + label
+			lastEnd = end
+
+			linkLines = append(linkLines, fmt.Sprintf("=> %s %s", url, label))
+		}
+
+		plainLine += line[lastEnd:]
+
+		gemtextLines = append(gemtextLines, plainLine)
+		gemtextLines = append(gemtextLines, linkLines...)
+	}
+
+	return strings.Join(gemtextLines, "\n"), nil
+}
+
+// UnknownOutputFormatErr is returned when a caller asks for an OutputFormat that has not been registered.
+type UnknownOutputFormatErr struct {
+	name string
+}
+
+func (err UnknownOutputFormatErr) Error() string {
+	return fmt.Sprintf("No OutputFormat registered with name '%s'", err.name)
+}
@@ -0,0 +1,47 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderJsonOutputFormatIncludesBreadcrumbs(t *testing.T) {
+	t.Parallel()
+
+	contentMap := NewContentMap()
+	ancestor := newTestPage("packages/module-vpc/README.md")
+	ancestor.Title = "Module Vpc"
+	page := newTestPage("packages/module-vpc/vpc-app/README.md")
+	page.Title = "Vpc App"
+	contentMap.AddPage(ancestor)
+	contentMap.AddPage(page)
+
+	out, err := renderJsonOutputFormat(page, contentMap)
+	assert.Nil(t, err)
+	assert.Contains(t, out, `"breadcrumbs":["Module Vpc"]`)
+}
+
+func TestRenderGemtextOutputFormatTranslatesHeadingsAndLinks(t *testing.T) {
+	t.Parallel()
+
+	page := &Page{
+		BodyMarkdown: "# Title\n\nSee [the VPC module](/packages/module-vpc/vpc-app/README.html) for details.",
+	}
+
+	out, err := renderGemtextOutputFormat(page, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Title\n\nSee the VPC module for details.\n=> /packages/module-vpc/vpc-app/README.html the VPC module", out)
+}
+
+func TestRenderGemtextOutputFormatLeavesImagesUntouched(t *testing.T) {
+	t.Parallel()
+
+	page := &Page{
+		BodyMarkdown: "![Diagram](./diagram.png)",
+	}
+
+	out, err := renderGemtextOutputFormat(page, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "![Diagram](./diagram.png)", out)
+}
@@ -5,7 +5,7 @@ import (
 	"regexp"
 	"strings"
 	"github.com/gruntwork-io/docs/errors"
-	"github.com/gruntwork-io/docs/file"
+	"github.com/spf13/afero"
 	"bytes"
 	"fmt"
 	"github.com/shurcooL/github_flavored_markdown"
@@ -16,34 +16,58 @@ const FILE_PATHS_REGEX = `(?:http:/|https:/)?(/[A-Za-z0-9_/.-]+)|([A-Za-z0-9_/.-
 const PACKAGE_GITHUB_REPO_URL_PREFIX = "https://github.com/gruntwork-io/<package-name>/tree/master"
 const PACKAGE_FILE_REGEX = `^packages/([\w -]+)(/.*)$`
 const PACKAGE_FILE_REGEX_NUM_CAPTURE_GROUPS = 2
-const MARKDOWN_FILE_PATH_REGEX = `^.*/(.*)\.md$`
-const MARKDOWN_FILE_PATH_REGEX_NUM_CAPTURE_GROUPS = 1
-
 // TODO: Figure out better way to reference this file
 const HTML_TEMPLATE_REL_PATH = "_html/doc_template.html"
 
 // A Page represents a page of documentation, usually formatted as a markdown file.
 type Page struct {
 	File
-	Title        string  // the title of the page
-	BodyMarkdown string  // the body of the page as Markdown
-	BodyHtml     string  // the body of the page as HTML (does not include surrounding HTML)
-	GithubUrl    string  // the Gruntwork Repo GitHub URL to which this page corresponds
-	ParentFolder *Folder // the nav folder in which this page resides
+	Title        string   // the title of the page
+	BodyMarkdown string   // the body of the page as Markdown
+	BodyHtml     string   // the body of the page as HTML (does not include surrounding HTML)
+	GithubUrl    string   // the Gruntwork Repo GitHub URL to which this page corresponds
+	SourceFs     afero.Fs // filesystem InputPath/FullInputPath and the theme's HTML template are read from
+	DestFs       afero.Fs // filesystem WriteFullPageHtmlToOutputPath writes rendered output to
 }
 
-// Populate all the remaining properties of this Page instance
-func (p *Page) PopulateAllProperties() error {
+// Populate all the remaining properties of this Page instance. contentMap is used to resolve cross-references
+// in this page's links to other pages in the corpus, so it should already contain every page in the corpus.
+// linkFormat is the OutputFormat that cross-references in this page's body are resolved against (i.e. the
+// extension baked into every resolved link's URL) - pass the format actually being rendered/served, since a
+// resolved link hard-coded to one format's extension (e.g. ".html") is wrong for a corpus publishing in another.
+//
+// forceRefresh bypasses the cache read (a fresh result is still written back). The cache key is derived only
+// from this page's own file, so it has no way of knowing when a page it links to has been re-resolved to a new
+// URL elsewhere in the corpus; callers re-rendering this page because one of its dependencies changed, rather
+// than because its own file changed, must pass forceRefresh=true or the stale cached links will never update.
+func (p *Page) PopulateAllProperties(contentMap *ContentMap, forceRefresh bool, linkFormat OutputFormat) error {
 	var err error
 
 	p.Title = p.getTitle()
 
-	p.BodyMarkdown, err = p.getSanitizedMarkdownBody()
+	key, err := cacheKeyForFile(p.SourceFs, p.FullInputPath, linkFormat)
 	if err != nil {
 		return errors.WithStackTrace(err)
 	}
 
-	p.BodyHtml = getHtmlFromMarkdown(p.BodyMarkdown)
+	markdown, html, ok := "", "", false
+	if !forceRefresh {
+		markdown, html, ok = defaultCache.Get(key)
+	}
+
+	if ok {
+		p.BodyMarkdown = markdown
+		p.BodyHtml = html
+	} else {
+		p.BodyMarkdown, err = p.getSanitizedMarkdownBody(contentMap, linkFormat)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+
+		p.BodyHtml = getHtmlFromMarkdown(p.BodyMarkdown)
+
+		defaultCache.Set(key, p.BodyMarkdown, p.BodyHtml)
+	}
 
 	p.GithubUrl, err = convertPackageLinkToUrl(p.InputPath, "./")
 	if err != nil {
@@ -53,50 +77,62 @@ func (p *Page) PopulateAllProperties() error {
 	return nil
 }
 
-// Add this page to the NavTree that starts at the rootFolder, creating any necessary folders along the way.
-func (p *Page) AddToNavTree(rootFolder *Folder) error {
-	containingFolderPath := getContainingFolder(p.OutputPath)
-	containingFolder := rootFolder.CreateFolderIfNotExist(containingFolderPath)
-
-	containingFolder.AddPage(p)
+// Add this page to the given ContentMap, creating any necessary folder nodes along the way.
+func (p *Page) AddToNavTree(contentMap *ContentMap) error {
+	contentMap.AddPage(p)
 
 	return nil
 }
 
-// Get the folder that contains the file specified in the given path
-func getContainingFolder(path string) string {
-	return filepath.Dir(path)
-}
+// Write this page to disk once for each of the given OutputFormats (e.g. HTML, Markdown, JSON, Gemtext). An empty
+// formats list falls back to GetOutputFormats(nil), preserving the original HTML-only behavior. docs-preprocessor's
+// RunServe passes the formats resolved from ServeOpts.EnabledFormats.
+func (p *Page) WriteFullPageHtmlToOutputPath(contentMap *ContentMap, rootOutputPath string, formats []OutputFormat) error {
+	if len(formats) == 0 {
+		var err error
+		formats, err = GetOutputFormats(nil)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
 
-// Output the full HTML body of this page
-func (p *Page) WriteFullPageHtmlToOutputPath(rootFolder *Folder, rootOutputPath string) error {
-	bodyHtml := p.getBodyHtml()
-	navTreeHtml := p.getNavTreeHtml(rootFolder)
+	targetPathBuilder := &TargetPathBuilder{RootOutputPath: rootOutputPath}
 
-	fullHtml, err := getFullHtml(bodyHtml, navTreeHtml, p.Title)
-	if err != nil {
-		return errors.WithStackTrace(err)
-	}
+	for _, format := range formats {
+		output, err := format.Render(p, contentMap)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
 
-	absOutputPath := filepath.Join(rootOutputPath, p.OutputPath)
-	absOutputPathDotHtml, err := replaceMdFileExtensionWithHtmlFileExtension(absOutputPath)
-	if err != nil {
-		return errors.WithStackTrace(err)
-	}
+		targetPath := targetPathBuilder.Build(p, format)
 
-	fmt.Printf("Outputting %s to %s\n", p.InputPath, absOutputPathDotHtml)
+		fmt.Printf("Outputting %s to %s\n", p.InputPath, targetPath)
 
-	err = file.WriteFile(fullHtml, absOutputPathDotHtml)
-	if err != nil {
-		return errors.WithStackTrace(err)
+		err = afero.WriteFile(p.DestFs, targetPath, []byte(output), 0644)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
 	}
 
 	return nil
 }
 
-// Get the NavTree of the given Root Folder with the current page as the "active" page as HTML
-func (p *Page) getNavTreeHtml(rootFolder *Folder) template.HTML {
-	return rootFolder.GetAsNavTreeHtml(p)
+// Get the NavTree of the given ContentMap with the current page as the "active" page as HTML
+func (p *Page) getNavTreeHtml(contentMap *ContentMap) template.HTML {
+	return contentMap.GetAsNavTreeHtml(p)
+}
+
+// getBreadcrumbTitles returns the Titles of every ancestor folder of this page that has one (e.g. a README.md),
+// root first, for use as a breadcrumb trail.
+func (p *Page) getBreadcrumbTitles(contentMap *ContentMap) []string {
+	titles := []string{}
+
+	ancestors := contentMap.Ancestors(p.OutputPath)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		titles = append(titles, ancestors[i].Title)
+	}
+
+	return titles
 }
 
 // Get the NavTree of the givn Root Folder with the current page as the "active" page as HTML
@@ -104,10 +140,12 @@ func (p *Page) getBodyHtml() template.HTML {
 	return template.HTML(p.BodyHtml)
 }
 
-// Return a NewPage
-func NewPage(file *File) *Page {
+// Return a NewPage backed by the given source and destination filesystems.
+func NewPage(file *File, sourceFs afero.Fs, destFs afero.Fs) *Page {
 	return &Page{
-		File: *file,
+		File:     *file,
+		SourceFs: sourceFs,
+		DestFs:   destFs,
 	}
 }
 
@@ -120,15 +158,16 @@ func (p *Page) getTitle() string {
 }
 
 // Get the Page's markdown body, sanitized for public HTML output (i.e. convert inline links to fully qualified URLs)
-func (p *Page) getSanitizedMarkdownBody() (string, error) {
+func (p *Page) getSanitizedMarkdownBody(contentMap *ContentMap, linkFormat OutputFormat) (string, error) {
 	var body string
 
-	body, err := file.ReadFile(p.FullInputPath)
+	bodyBytes, err := afero.ReadFile(p.SourceFs, p.FullInputPath)
 	if err != nil {
 		return body, errors.WithStackTrace(err)
 	}
+	body = string(bodyBytes)
 
-	body, err = convertMarkdownLinksToUrls(p.InputPath, body)
+	body, err = convertMarkdownLinksToUrls(contentMap, p, body, linkFormat)
 	if err != nil {
 		return body, errors.WithStackTrace(err)
 	}
@@ -136,16 +175,18 @@ func (p *Page) getSanitizedMarkdownBody() (string, error) {
 	return body, nil
 }
 
-// Given a doc file with the given body at the given inputPath, convert all paths in the body (e.g. "/foo" or "../bar")
-// to fully qualified URLs.
-func convertMarkdownLinksToUrls(inputPath, body string) (string, error) {
+// Given a doc file with the given body belonging to fromPage, convert all paths in the body (e.g. "/foo" or
+// "../bar") to fully qualified URLs. Links are first resolved against contentMap so that intra-corpus links
+// point at the resolved page's own rendered URL as linkFormat; only links that don't resolve to another page in
+// the corpus fall back to the GitHub-URL behavior of convertPackageLinkToUrl.
+func convertMarkdownLinksToUrls(contentMap *ContentMap, fromPage *Page, body string, linkFormat OutputFormat) (string, error) {
 	var newBody string
 
 	newBody = body
 	linkPaths := getAllLinkPaths(body)
 
 	for _, linkPath := range linkPaths {
-		url, err := convertPackageLinkToUrl(inputPath, linkPath)
+		url, err := resolveLinkUrl(contentMap, fromPage, linkPath, linkFormat)
 		if err != nil {
 			return newBody, errors.WithStackTrace(err)
 		}
@@ -167,6 +208,24 @@ func convertMarkdownLinksToUrls(inputPath, body string) (string, error) {
 	return newBody, nil
 }
 
+// resolveLinkUrl resolves linkPath against contentMap first, returning the resolved page's own URL as rendered
+// in linkFormat. If linkPath doesn't resolve to another page in the corpus, it prints a build-time warning (so
+// broken links surface in CI) and falls back to the original GitHub-URL behavior.
+func resolveLinkUrl(contentMap *ContentMap, fromPage *Page, linkPath string, linkFormat OutputFormat) (string, error) {
+	if contentMap != nil {
+		if resolvedPage, err := contentMap.GetPage(linkPath, fromPage); err == nil {
+			contentMap.RecordDependency(fromPage.OutputPath, resolvedPage.OutputPath)
+
+			targetPathBuilder := &TargetPathBuilder{}
+			return targetPathBuilder.BuildUrl(resolvedPage, linkFormat), nil
+		}
+
+		fmt.Printf("WARNING: could not resolve ref '%s' referenced from %s\n", linkPath, fromPage.InputPath)
+	}
+
+	return convertPackageLinkToUrl(fromPage.InputPath, linkPath)
+}
+
 // Given a body of text find all instances of link paths (e.g. /foo or ../bar)
 func getAllLinkPaths(body string) []string {
 	var relPaths []string
@@ -277,28 +336,10 @@ func getHtmlFromMarkdown(markdown string) string {
 	return string(bytesOutput)
 }
 
-// Given a path like /foo/bar.md, return /foo/bar.html
-func replaceMdFileExtensionWithHtmlFileExtension(path string) (string, error) {
-	var updatedPath string
-
-	regex := regexp.MustCompile(MARKDOWN_FILE_PATH_REGEX)
-	submatches := regex.FindAllStringSubmatch(path, -1)
-
-	if len(submatches) == 0 || len(submatches[0]) != MARKDOWN_FILE_PATH_REGEX_NUM_CAPTURE_GROUPS + 1 {
-		return updatedPath, errors.WithStackTrace(&WrongNumberOfCaptureGroupsReturnedFromPageRegEx{inputPath: path, regExName: "MARKDOWN_FILE_PATH_REGEX", regEx: MARKDOWN_FILE_PATH_REGEX })
-	}
-
-	filename := submatches[0][1]
-	filenameDotMd := fmt.Sprintf("%s.%s", filename, "md")
-	filenameDotHtml := fmt.Sprintf("%s.%s", filename, "html")
-
-	updatedPath = strings.Replace(path, filenameDotMd, filenameDotHtml, -1)
-
-	return updatedPath, nil
-}
-
-// Return the full HTML rendering of this page
-func getFullHtml(pageBodyHtml template.HTML, navTreeHtml template.HTML, pageTitle string) (string, error) {
+// Return the full HTML rendering of this page. The HTML template is read from sourceFs, so a theme directory
+// overlaid onto sourceFs (see NewSourceFs) can supply HTML_TEMPLATE_REL_PATH when the corpus doesn't have its
+// own copy.
+func getFullHtml(sourceFs afero.Fs, pageBodyHtml template.HTML, navTreeHtml template.HTML, pageTitle string) (string, error) {
 	var templateOutput string
 
 	type htmlTemplateProperties struct {
@@ -308,10 +349,11 @@ func getFullHtml(pageBodyHtml template.HTML, navTreeHtml template.HTML, pageTitl
 	}
 
 	htmlTemplatePath := filepath.Join(HTML_TEMPLATE_REL_PATH)
-	htmlTemplateBody, err := file.ReadFile(htmlTemplatePath)
+	htmlTemplateBodyBytes, err := afero.ReadFile(sourceFs, htmlTemplatePath)
 	if err != nil {
 		return templateOutput, errors.WithStackTrace(err)
 	}
+	htmlTemplateBody := string(htmlTemplateBodyBytes)
 
 	htmlTemplate, err := template.New(pageTitle).Parse(htmlTemplateBody)
 	if err != nil {
@@ -327,5 +369,13 @@ func getFullHtml(pageBodyHtml template.HTML, navTreeHtml template.HTML, pageTitl
 
 	templateOutput = buf.String()
 
+	if LiveReloadScript != "" {
+		templateOutput = strings.Replace(templateOutput, "</body>", LiveReloadScript+"</body>", 1)
+	}
+
 	return templateOutput, nil
-}
\ No newline at end of file
+}
+
+// LiveReloadScript, if non-empty, is appended just before </body> in every rendered HTML page. The `docs serve`
+// subcommand sets this so browsers viewing the site auto-reload after an incremental rebuild.
+var LiveReloadScript string
\ No newline at end of file
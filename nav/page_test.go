@@ -0,0 +1,83 @@
+package nav
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPopulateAllPropertiesForceRefreshBypassesStaleCache(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(fs, "global/help/support.md", []byte("stale body"), 0644))
+
+	page := &Page{
+		File: File{
+			InputPath:     "global/help/support.md",
+			FullInputPath: "global/help/support.md",
+			OutputPath:    "global/help/support.md",
+		},
+		SourceFs: fs,
+	}
+
+	contentMap := NewContentMap()
+	contentMap.AddPage(page)
+
+	format, err := GetOutputFormat("html")
+	assert.Nil(t, err)
+
+	assert.Nil(t, page.PopulateAllProperties(contentMap, false, format))
+	assert.Equal(t, "stale body", page.BodyMarkdown)
+
+	// The rewrite keeps the file's size and its mtime is pinned back to the original, so the cache key is
+	// unchanged and a normal (non-forced) re-populate must hit the cache and keep returning the stale body.
+	info, err := fs.Stat("global/help/support.md")
+	assert.Nil(t, err)
+	modTime := info.ModTime()
+	assert.Nil(t, afero.WriteFile(fs, "global/help/support.md", []byte("fresh body"), 0644))
+	assert.Nil(t, fs.Chtimes("global/help/support.md", modTime, modTime))
+	assert.Nil(t, page.PopulateAllProperties(contentMap, false, format))
+	assert.Equal(t, "stale body", page.BodyMarkdown, "cache key is unaffected by content written out-of-band, so this should still be the cached value")
+
+	// forceRefresh must bypass that cache hit and re-read the file, as rebuildAffectedPages relies on for a
+	// page's dependents when only the dependency (not the dependent's own file) changed.
+	assert.Nil(t, page.PopulateAllProperties(contentMap, true, format))
+	assert.Equal(t, "fresh body", page.BodyMarkdown)
+}
+
+func TestPopulateAllPropertiesResolvesLinksAgainstLinkFormat(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(fs, "global/help/support.md", []byte("see [other](/global/help/other.md)"), 0644))
+	assert.Nil(t, afero.WriteFile(fs, "global/help/other.md", []byte("other body"), 0644))
+
+	page := &Page{
+		File: File{
+			InputPath:     "global/help/support.md",
+			FullInputPath: "global/help/support.md",
+			OutputPath:    "global/help/support.md",
+		},
+		SourceFs: fs,
+	}
+	otherPage := &Page{
+		File: File{
+			InputPath:     "global/help/other.md",
+			FullInputPath: "global/help/other.md",
+			OutputPath:    "global/help/other.md",
+		},
+		SourceFs: fs,
+	}
+
+	contentMap := NewContentMap()
+	contentMap.AddPage(page)
+	contentMap.AddPage(otherPage)
+
+	gemtextFormat, err := GetOutputFormat("gemtext")
+	assert.Nil(t, err)
+
+	assert.Nil(t, page.PopulateAllProperties(contentMap, false, gemtextFormat))
+	assert.Contains(t, page.BodyMarkdown, "/global/help/other.gmi", "link should be resolved with the gemtext extension, not hard-coded to .html")
+}
@@ -0,0 +1,39 @@
+package nav
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fallbackSystemMemoryBytes is used when /proc/meminfo can't be read: 4 GiB, a conservative guess that keeps
+// DefaultCacheBudgetBytes at its 1 GiB cap.
+const fallbackSystemMemoryBytes = int64(4) << 30
+
+// totalSystemMemoryBytes reads MemTotal out of /proc/meminfo. If it can't be read for any reason, it falls back
+// to fallbackSystemMemoryBytes so that DefaultCacheBudgetBytes always has something sane to divide by four.
+func totalSystemMemoryBytes() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return fallbackSystemMemoryBytes
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return fallbackSystemMemoryBytes
+		}
+
+		return kilobytes * 1024
+	}
+
+	return fallbackSystemMemoryBytes
+}
@@ -0,0 +1,12 @@
+// +build !linux
+
+package nav
+
+// fallbackSystemMemoryBytes stands in for total system memory on this platform: 4 GiB, which keeps
+// DefaultCacheBudgetBytes at its 1 GiB cap.
+const fallbackSystemMemoryBytes = int64(4) << 30
+
+// totalSystemMemoryBytes returns fallbackSystemMemoryBytes on platforms without a /proc/meminfo-style API.
+func totalSystemMemoryBytes() int64 {
+	return fallbackSystemMemoryBytes
+}